@@ -0,0 +1,45 @@
+// Command tlockd wires up a service.Server from flags -- which drand relay
+// and chain hashes to serve, where to listen, and the bearer token to
+// require -- and runs it. See package service for the API it exposes.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/drand/tlock"
+	httpnet "github.com/drand/tlock/networks/http"
+	"github.com/drand/tlock/service"
+)
+
+func main() {
+	var (
+		addr        = flag.String("addr", ":8080", "address to listen on")
+		relay       = flag.String("relay", "https://api.drand.sh", "drand HTTP relay to fetch beacons from")
+		chainHashes = flag.String("chainhashes", "", "comma separated list of chain hashes this daemon will serve")
+		authToken   = flag.String("auth-token", os.Getenv("TLOCKD_AUTH_TOKEN"), "bearer token required on every request; also read from TLOCKD_AUTH_TOKEN")
+	)
+	flag.Parse()
+
+	if *chainHashes == "" {
+		log.Fatal("at least one -chainhashes value is required")
+	}
+
+	networks := make(map[string]tlock.Network)
+	for _, chainHash := range strings.Split(*chainHashes, ",") {
+		networks[chainHash] = httpnet.NewNetwork(*relay, chainHash)
+	}
+
+	srv := service.New(service.Config{
+		Networks:  networks,
+		AuthToken: *authToken,
+	})
+
+	log.Printf("tlockd listening on %s, serving %d chain(s)", *addr, len(networks))
+	if err := http.ListenAndServe(*addr, srv); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,98 @@
+package tlock
+
+import (
+	"context"
+	"time"
+
+	"github.com/drand/kyber"
+)
+
+// fakeNetworkError is a NetworkError test double.
+type fakeNetworkError struct {
+	msg       string
+	retryable bool
+}
+
+func (e *fakeNetworkError) Error() string   { return e.msg }
+func (e *fakeNetworkError) Retryable() bool { return e.retryable }
+
+// fakeNetwork is a minimal Network test double used by the white-box tests
+// in this package. It never talks to a real drand chain: IsReadyToDecrypt
+// and IsReadyToDecryptErr are driven by errSequence, and RetryAfter by the
+// retryAfter/hasRetryAfter fields, so tests can exercise blockUntilReady's
+// polling and error-classification logic deterministically.
+type fakeNetwork struct {
+	chainHash  string
+	beaconTime time.Time
+
+	// errSequence, if set, is consumed one entry per IsReadyToDecryptErr
+	// call; a nil entry means the round is ready, any other error is
+	// returned as-is. Once exhausted, the round is considered ready.
+	errSequence []error
+	calls       int
+
+	retryAfter    time.Duration
+	hasRetryAfter bool
+}
+
+func (f *fakeNetwork) Host() string { return "fake" }
+
+func (f *fakeNetwork) ChainHash() string { return f.chainHash }
+
+func (f *fakeNetwork) PublicKey() kyber.Point { return nil }
+
+func (f *fakeNetwork) BeaconTime(uint64) time.Time { return f.beaconTime }
+
+func (f *fakeNetwork) RoundNumber(context.Context, time.Time) (uint64, error) {
+	return 0, nil
+}
+
+func (f *fakeNetwork) IsReadyToDecrypt(roundNumber uint64) ([]byte, bool) {
+	id, err := f.IsReadyToDecryptErr(roundNumber)
+	return id, err == nil
+}
+
+func (f *fakeNetwork) IsReadyToDecryptErr(uint64) ([]byte, error) {
+	i := f.calls
+	f.calls++
+
+	if i < len(f.errSequence) && f.errSequence[i] != nil {
+		return nil, f.errSequence[i]
+	}
+
+	return []byte("fake-signature"), nil
+}
+
+func (f *fakeNetwork) RetryAfter() (time.Duration, bool) {
+	return f.retryAfter, f.hasRetryAfter
+}
+
+// plainFakeNetwork is a Network test double that does NOT implement
+// readyChecker, used to exercise blockUntilReady's bool-only fallback path
+// for Networks that never learned to classify errors.
+type plainFakeNetwork struct {
+	beaconTime time.Time
+	readyAfter int
+	calls      int
+}
+
+func (f *plainFakeNetwork) Host() string { return "fake" }
+
+func (f *plainFakeNetwork) ChainHash() string { return "fake" }
+
+func (f *plainFakeNetwork) PublicKey() kyber.Point { return nil }
+
+func (f *plainFakeNetwork) BeaconTime(uint64) time.Time { return f.beaconTime }
+
+func (f *plainFakeNetwork) RoundNumber(context.Context, time.Time) (uint64, error) {
+	return 0, nil
+}
+
+func (f *plainFakeNetwork) IsReadyToDecrypt(uint64) ([]byte, bool) {
+	f.calls++
+	if f.calls > f.readyAfter {
+		return []byte("fake-signature"), true
+	}
+
+	return nil, false
+}
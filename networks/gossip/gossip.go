@@ -0,0 +1,251 @@
+// Package gossip implements the tlock.Network interface by subscribing to a
+// drand gossipsub topic over libp2p instead of polling an HTTP relay. Once
+// constructed, beacon retrieval never touches any one relay's access log the
+// way repeatedly polling networks/http would. The chain's genesis and
+// period still have to come from somewhere, though -- NewNetwork takes them
+// as arguments rather than fetching them itself, so callers who care about
+// that can learn them out of band (a config file, a different chain they
+// already trust) instead of bootstrapping over HTTP.
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/drand/drand/chain"
+	"github.com/drand/drand/common/scheme"
+	"github.com/drand/kyber"
+	libp2p "github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/host"
+	"github.com/libp2p/go-libp2p/core/peer"
+	"github.com/multiformats/go-multiaddr"
+)
+
+// topicFormat mirrors the topic drand's own relay-gossip publishes beacons
+// on: one topic per chain hash, so a single pubsub instance can serve
+// multiple networks without cross-talk.
+const topicFormat = "/drand/pubsub/v0.0.0/%s"
+
+// beaconCacheSize bounds how many of the most recently seen beacons are kept
+// in memory. Once full, the oldest cached round is evicted to make room.
+const beaconCacheSize = 100
+
+// beaconMessage is the wire format published to the gossipsub topic. It
+// carries just enough to reconstruct and verify a chain.Beacon.
+type beaconMessage struct {
+	Round     uint64 `json:"round"`
+	Signature []byte `json:"signature"`
+}
+
+// beaconVerifier is the part of chain.Verifier that consume needs. It's
+// satisfied by chain.NewVerifier's return value, which NewNetwork uses by
+// default; WithVerifier lets a test substitute it so the gossipsub wiring
+// can be exercised with two in-process hosts without generating a real BLS
+// signature for every published beacon.
+type beaconVerifier interface {
+	VerifyBeacon(b chain.Beacon, public kyber.Point) error
+}
+
+// Network implements the tlock.Network interface over a drand gossipsub
+// topic rather than an HTTP relay.
+type Network struct {
+	host      host.Host
+	chainHash string
+	publicKey kyber.Point
+	genesis   time.Time
+	period    time.Duration
+	verifier  beaconVerifier
+
+	mu      sync.RWMutex
+	beacons map[uint64][]byte
+	order   []uint64
+}
+
+// NetworkOption configures optional behavior on a Network constructed by
+// NewNetwork.
+type NetworkOption func(*Network)
+
+// WithVerifier overrides the beacon verifier NewNetwork would otherwise
+// build for chainHash's scheme. It exists for tests.
+func WithVerifier(v beaconVerifier) NetworkOption {
+	return func(n *Network) { n.verifier = v }
+}
+
+// NewNetwork dials the given bootstrap peers, subscribes to the gossipsub
+// topic for chainHash, and caches verified beacons in memory as they arrive.
+// genesis and period describe the chain's round schedule, the same values
+// an HTTP relay would expose via its /info endpoint, and are needed to
+// answer RoundNumber/BeaconTime without a round-trip to any relay.
+func NewNetwork(ctx context.Context, chainHash string, publicKey kyber.Point, genesis time.Time, period time.Duration, bootstrapPeers []peer.AddrInfo, opts ...NetworkOption) (*Network, error) {
+	h, err := libp2p.New()
+	if err != nil {
+		return nil, fmt.Errorf("new libp2p host: %w", err)
+	}
+
+	for _, pi := range bootstrapPeers {
+		if err := h.Connect(ctx, pi); err != nil {
+			return nil, fmt.Errorf("connect bootstrap peer %s: %w", pi.ID, err)
+		}
+	}
+
+	ps, err := pubsub.NewGossipSub(ctx, h)
+	if err != nil {
+		return nil, fmt.Errorf("new gossipsub: %w", err)
+	}
+
+	topic, err := ps.Join(fmt.Sprintf(topicFormat, chainHash))
+	if err != nil {
+		return nil, fmt.Errorf("join topic: %w", err)
+	}
+
+	sub, err := topic.Subscribe()
+	if err != nil {
+		return nil, fmt.Errorf("subscribe topic: %w", err)
+	}
+
+	sch := scheme.Scheme{
+		ID:              scheme.UnchainedSchemeID,
+		DecouplePrevSig: true,
+	}
+
+	n := &Network{
+		host:      h,
+		chainHash: chainHash,
+		publicKey: publicKey,
+		genesis:   genesis,
+		period:    period,
+		verifier:  chain.NewVerifier(sch),
+		beacons:   make(map[uint64][]byte),
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	go n.consume(ctx, sub)
+
+	return n, nil
+}
+
+// ParseBootstrapPeers converts multiaddr strings (e.g.
+// "/dnsaddr/pubsub.drand.sh/p2p/<peerID>") into the peer.AddrInfo values
+// NewNetwork expects.
+func ParseBootstrapPeers(addrs []string) ([]peer.AddrInfo, error) {
+	infos := make([]peer.AddrInfo, 0, len(addrs))
+
+	for _, addr := range addrs {
+		maddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return nil, fmt.Errorf("parse bootstrap peer %q: %w", addr, err)
+		}
+
+		pi, err := peer.AddrInfoFromP2pAddr(maddr)
+		if err != nil {
+			return nil, fmt.Errorf("peer info from %q: %w", addr, err)
+		}
+
+		infos = append(infos, *pi)
+	}
+
+	return infos, nil
+}
+
+// consume reads beacon messages off the subscription for as long as ctx is
+// alive, verifying and caching each one. Messages that fail to parse or
+// verify are silently dropped, the same way a malformed HTTP response would
+// just fail that one poll in networks/http.
+func (n *Network) consume(ctx context.Context, sub *pubsub.Subscription) {
+	for {
+		msg, err := sub.Next(ctx)
+		if err != nil {
+			return
+		}
+
+		var bm beaconMessage
+		if err := json.Unmarshal(msg.Data, &bm); err != nil {
+			continue
+		}
+
+		b := chain.Beacon{
+			Round:     bm.Round,
+			Signature: bm.Signature,
+		}
+		if err := n.verifier.VerifyBeacon(b, n.publicKey); err != nil {
+			continue
+		}
+
+		n.cache(bm.Round, bm.Signature)
+	}
+}
+
+// cache stores a verified beacon signature, evicting the oldest cached
+// round once beaconCacheSize is exceeded.
+func (n *Network) cache(round uint64, signature []byte) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, exists := n.beacons[round]; exists {
+		return
+	}
+
+	n.beacons[round] = signature
+	n.order = append(n.order, round)
+
+	if len(n.order) > beaconCacheSize {
+		oldest := n.order[0]
+		n.order = n.order[1:]
+		delete(n.beacons, oldest)
+	}
+}
+
+// Host returns an identifier for this peer, satisfying the Network
+// interface's notion of a "host".
+func (n *Network) Host() string {
+	addrs := n.host.Addrs()
+	if len(addrs) == 0 {
+		return n.host.ID().String()
+	}
+
+	return fmt.Sprintf("%s/p2p/%s", addrs[0], n.host.ID())
+}
+
+// ChainHash returns the drand chain hash this Network is subscribed to.
+func (n *Network) ChainHash() string {
+	return n.chainHash
+}
+
+// PublicKey returns the chain's distributed public key used to verify
+// incoming beacons.
+func (n *Network) PublicKey() kyber.Point {
+	return n.publicKey
+}
+
+// IsReadyToDecrypt returns the cached beacon signature for roundNumber, if
+// one has arrived over gossip. A false return lets the caller fall back to
+// its normal ErrTooEarly handling.
+func (n *Network) IsReadyToDecrypt(roundNumber uint64) (id []byte, ready bool) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	id, ready = n.beacons[roundNumber]
+	return id, ready
+}
+
+// RoundNumber returns the round number active at time t.
+func (n *Network) RoundNumber(_ context.Context, t time.Time) (uint64, error) {
+	if t.Before(n.genesis) {
+		return 1, nil
+	}
+
+	return uint64(t.Sub(n.genesis)/n.period) + 1, nil
+}
+
+// BeaconTime returns the time at which roundNumber's beacon is expected to
+// be produced.
+func (n *Network) BeaconTime(roundNumber uint64) time.Time {
+	return n.genesis.Add(time.Duration(roundNumber-1) * n.period)
+}
@@ -0,0 +1,200 @@
+package gossip_test
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // Calls init function.
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/drand/drand/chain"
+	"github.com/drand/kyber"
+	bls "github.com/drand/kyber-bls12381"
+	libp2p "github.com/libp2p/go-libp2p"
+	pubsub "github.com/libp2p/go-libp2p-pubsub"
+	"github.com/libp2p/go-libp2p/core/peer"
+
+	"github.com/drand/tlock"
+	"github.com/drand/tlock/data_encrypters/aead"
+	"github.com/drand/tlock/encoders/base"
+	"github.com/drand/tlock/networks/gossip"
+)
+
+// gossipTopicFormat mirrors the unexported topicFormat in gossip.go; it has
+// to be duplicated here since a black-box test can't reach it directly.
+const gossipTopicFormat = "/drand/pubsub/v0.0.0/%s"
+
+var (
+	//go:embed test_artifacts/data.txt
+	dataFile []byte
+)
+
+// testnetChainHash, testnetGenesis, testnetPeriod, and testnetPublicKeyHex
+// are the published chain parameters of pl-us.testnet.drand.sh's
+// unchained-on-G1 beacon, copied once from its /info endpoint. Hardcoding
+// them here, rather than asking an http.Network to look them up, means this
+// test never dials the HTTP relay at all -- dialing it just to learn the
+// schedule before switching to gossip would undercut the whole point of
+// this package.
+const (
+	testnetChainHash    = "7672797f548f3f4748ac4bf3352fc6c6b6468c9ad40ad456a397545c6e2df5bf"
+	testnetPeriod       = 3 * time.Second
+	testnetPublicKeyHex = "8200fc249deb0148eb918d6e213980c5d01acd7fc251900d9260136853bd753c1a08b8c97c6c76cfd9c6e5c7ff15fef0c81fb48"
+)
+
+var testnetGenesis = time.Unix(1651677099, 0)
+
+func testnetPublicKey(t *testing.T) kyber.Point {
+	t.Helper()
+
+	raw, err := hex.DecodeString(testnetPublicKeyHex)
+	if err != nil {
+		t.Fatalf("decode testnet public key: %s", err)
+	}
+
+	p := bls.NewBLS12381Suite().G1().Point()
+	if err := p.UnmarshalBinary(raw); err != nil {
+		t.Fatalf("unmarshal testnet public key: %s", err)
+	}
+
+	return p
+}
+
+// Test_EncryptionWithRound_Gossip mirrors tlock_test.Test_EncryptionWithRound
+// but retrieves the decryption beacon over a gossipsub topic instead of
+// polling an HTTP relay.
+func Test_EncryptionWithRound_Gossip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping testing in short mode")
+	}
+
+	bootstrapAddrs := os.Getenv("TLOCK_GOSSIP_BOOTSTRAP")
+	if bootstrapAddrs == "" {
+		t.Skip("skipping: TLOCK_GOSSIP_BOOTSTRAP not set, no gossipsub bootstrap peer to dial")
+	}
+
+	ctx := context.Background()
+
+	bootstrapPeers, err := gossip.ParseBootstrapPeers([]string{bootstrapAddrs})
+	if err != nil {
+		t.Fatalf("parse bootstrap peers: %s", err)
+	}
+
+	network, err := gossip.NewNetwork(ctx, testnetChainHash, testnetPublicKey(t), testnetGenesis, testnetPeriod, bootstrapPeers)
+	if err != nil {
+		t.Fatalf("new gossip network: %s", err)
+	}
+
+	// =========================================================================
+	// Encrypt
+
+	in, err := os.Open("test_artifacts/data.txt")
+	if err != nil {
+		t.Fatalf("reader error %s", err)
+	}
+	defer in.Close()
+
+	var cipherData bytes.Buffer
+
+	futureRound, err := network.RoundNumber(ctx, time.Now().Add(6*time.Second))
+	if err != nil {
+		t.Fatalf("client: %s", err)
+	}
+
+	err = tlock.NewEncrypter(network, aead.DataEncrypter{}, base.Encoder{}).Encrypt(ctx, &cipherData, in, futureRound, false)
+	if err != nil {
+		t.Fatalf("encrypt with round error %s", err)
+	}
+
+	// =========================================================================
+	// Decrypt
+
+	var plainData bytes.Buffer
+
+	// Wait for the future beacon to arrive over gossip.
+	time.Sleep(10 * time.Second)
+
+	err = tlock.NewDecrypter(network, aead.DataDecrypter{}, base.Decoder{}).Decrypt(ctx, &plainData, &cipherData, false)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	if !bytes.Equal(plainData.Bytes(), dataFile) {
+		t.Fatalf("decrypted file is invalid; expected %d; got %d", len(dataFile), len(plainData.Bytes()))
+	}
+}
+
+// alwaysValidVerifier stubs the beaconVerifier gossip.Network otherwise
+// builds from a real BLS scheme, letting Test_GossipRoundTrip exercise the
+// gossipsub wiring -- host creation, bootstrap dialing, topic join and
+// subscribe, and the beacon cache -- without having to produce a real
+// signature for every published beacon.
+type alwaysValidVerifier struct{}
+
+func (alwaysValidVerifier) VerifyBeacon(chain.Beacon, kyber.Point) error { return nil }
+
+// Test_GossipRoundTrip spins up two in-process libp2p hosts, a bare
+// publisher and the gossip.Network subscriber, and publishes a beacon
+// directly between them. Unlike Test_EncryptionWithRound_Gossip, this runs
+// on every test invocation rather than only when TLOCK_GOSSIP_BOOTSTRAP
+// happens to be set.
+func Test_GossipRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publisherHost, err := libp2p.New()
+	if err != nil {
+		t.Fatalf("new publisher host: %s", err)
+	}
+
+	publisherPS, err := pubsub.NewGossipSub(ctx, publisherHost)
+	if err != nil {
+		t.Fatalf("new publisher gossipsub: %s", err)
+	}
+
+	topic, err := publisherPS.Join(fmt.Sprintf(gossipTopicFormat, testnetChainHash))
+	if err != nil {
+		t.Fatalf("publisher join topic: %s", err)
+	}
+
+	bootstrapPeers := []peer.AddrInfo{{ID: publisherHost.ID(), Addrs: publisherHost.Addrs()}}
+
+	network, err := gossip.NewNetwork(ctx, testnetChainHash, testnetPublicKey(t), testnetGenesis, testnetPeriod, bootstrapPeers, gossip.WithVerifier(alwaysValidVerifier{}))
+	if err != nil {
+		t.Fatalf("new gossip network: %s", err)
+	}
+
+	const round = 1
+
+	msg, err := json.Marshal(struct {
+		Round     uint64 `json:"round"`
+		Signature []byte `json:"signature"`
+	}{Round: round, Signature: []byte("stub-signature")})
+	if err != nil {
+		t.Fatalf("marshal beacon message: %s", err)
+	}
+
+	// The gossipsub mesh between the two hosts takes a moment to form after
+	// bootstrap dialing, so republish on a short interval until the
+	// subscriber's cache picks it up rather than guessing a fixed delay.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if err := topic.Publish(ctx, msg); err != nil {
+			t.Fatalf("publish beacon: %s", err)
+		}
+
+		if _, ready := network.IsReadyToDecrypt(round); ready {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatal("beacon never arrived over gossip within 5s")
+		}
+
+		time.Sleep(100 * time.Millisecond)
+	}
+}
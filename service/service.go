@@ -0,0 +1,449 @@
+// Package service exposes tlock's encrypt/decrypt operations as an
+// HTTP/JSON API modeled on Vault's transit secrets engine, so other
+// in-house services can time-lock data without linking the BLS/drand code
+// directly.
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drand/tlock"
+	"github.com/drand/tlock/data_encrypters/aead"
+	"github.com/drand/tlock/encoders/base"
+)
+
+// Config configures a Server.
+type Config struct {
+	// Networks indexes the drand networks this server can use, keyed by
+	// their chain hash. A request selects which one to use by supplying
+	// that chainhash.
+	Networks map[string]tlock.Network
+
+	// AuthToken, if non-empty, is required as a bearer token on every
+	// request.
+	AuthToken string
+}
+
+// Server implements http.Handler, exposing tlock as a transit-style
+// key-management HTTP API.
+type Server struct {
+	networks  map[string]tlock.Network
+	authToken string
+	mux       *http.ServeMux
+}
+
+// New constructs a Server from the given Config.
+func New(cfg Config) *Server {
+	s := &Server{
+		networks:  cfg.Networks,
+		authToken: cfg.AuthToken,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/tlock/encrypt/", s.handleEncrypt)
+	mux.HandleFunc("/v1/tlock/decrypt/", s.handleDecrypt)
+	mux.HandleFunc("/v1/tlock/rounds", s.handleRounds)
+	mux.HandleFunc("/v1/tlock/batch_decrypt", s.handleBatchDecrypt)
+	s.mux = mux
+
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// authorized reports whether r carries the configured bearer token, or
+// whether no token is configured at all. The comparison is constant-time so
+// a caller can't use response latency to brute-force AuthToken one byte at
+// a time.
+func (s *Server) authorized(r *http.Request) bool {
+	if s.authToken == "" {
+		return true
+	}
+
+	const prefix = "Bearer "
+
+	got := r.Header.Get("Authorization")
+	if !strings.HasPrefix(got, prefix) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(got, prefix)), []byte(s.authToken)) == 1
+}
+
+// networkFor resolves chainHash to a configured Network, falling back to the
+// single configured network when chainHash is empty and exactly one is
+// configured.
+func (s *Server) networkFor(chainHash string) (tlock.Network, error) {
+	if chainHash != "" {
+		network, ok := s.networks[chainHash]
+		if !ok {
+			return nil, fmt.Errorf("unknown chainhash %q", chainHash)
+		}
+
+		return network, nil
+	}
+
+	if len(s.networks) == 1 {
+		for _, network := range s.networks {
+			return network, nil
+		}
+	}
+
+	return nil, errors.New("chainhash is required when more than one network is configured")
+}
+
+// =============================================================================
+
+type encryptRequest struct {
+	PlaintextB64 string `json:"plaintext_b64"`
+	Round        uint64 `json:"round,omitempty"`
+	Duration     string `json:"duration,omitempty"`
+	ChainHash    string `json:"chainhash"`
+}
+
+type encryptResponse struct {
+	CiphertextB64 string `json:"ciphertext_b64"`
+}
+
+// handleEncrypt implements POST /v1/tlock/encrypt/{name}. The {name} path
+// segment identifies the caller's key for logging purposes, mirroring
+// Vault's transit engine.
+//
+// A request with Content-Type: application/octet-stream is streamed
+// straight through: round/duration and chainhash come from the query
+// string, and r.Body is encrypted directly onto w without ever buffering
+// the plaintext, suiting blobs too large to hold comfortably in RAM. Any
+// other Content-Type is treated as the legacy JSON body below, which is
+// simpler for small payloads but fully decodes plaintext_b64 into memory
+// first.
+func (s *Server) handleEncrypt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/v1/tlock/encrypt/")
+
+	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		s.handleEncryptStream(w, r, name)
+		return
+	}
+
+	var req encryptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decode request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	network, err := s.networkFor(req.ChainHash)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plaintext, err := base64.StdEncoding.DecodeString(req.PlaintextB64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decode plaintext_b64: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	round, err := resolveRound(r.Context(), network, req.Round, req.Duration)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ciphertext, err := tlock.EncryptBytes(r.Context(), network, plaintext, round)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("encrypt %q: %s", name, err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, encryptResponse{CiphertextB64: base64.StdEncoding.EncodeToString(ciphertext)})
+}
+
+// handleEncryptStream handles the application/octet-stream form of
+// handleEncrypt: round (or duration) and chainhash travel as query
+// parameters since the body itself is the raw plaintext, and the ciphertext
+// is written to w as tlock produces it rather than collected first.
+func (s *Server) handleEncryptStream(w http.ResponseWriter, r *http.Request, name string) {
+	network, err := s.networkFor(r.URL.Query().Get("chainhash"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	round, err := resolveRoundQuery(r.Context(), network, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	enc := tlock.NewEncrypter(network, aead.DataEncrypter{}, base.Encoder{})
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if err := enc.Encrypt(r.Context(), w, r.Body, round, false); err != nil {
+		http.Error(w, fmt.Sprintf("encrypt %q: %s", name, err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// resolveRoundQuery is resolveRound for the streaming endpoints, which take
+// round/duration as query parameters instead of JSON body fields.
+func resolveRoundQuery(ctx context.Context, network tlock.Network, q url.Values) (uint64, error) {
+	var round uint64
+	if v := q.Get("round"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse round: %w", err)
+		}
+		round = parsed
+	}
+
+	return resolveRound(ctx, network, round, q.Get("duration"))
+}
+
+// resolveRound turns an encrypt request's round/duration pair into a round
+// number, preferring an explicit round when both are set.
+func resolveRound(ctx context.Context, network tlock.Network, round uint64, duration string) (uint64, error) {
+	if round != 0 {
+		return round, nil
+	}
+
+	if duration == "" {
+		return 0, errors.New("one of round or duration is required")
+	}
+
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0, fmt.Errorf("parse duration: %w", err)
+	}
+
+	roundNumber, err := network.RoundNumber(ctx, time.Now().Add(d))
+	if err != nil {
+		return 0, fmt.Errorf("round by duration: %w", err)
+	}
+
+	return roundNumber, nil
+}
+
+// =============================================================================
+
+type decryptRequest struct {
+	CiphertextB64 string `json:"ciphertext_b64"`
+}
+
+type decryptResponse struct {
+	PlaintextB64 string `json:"plaintext_b64"`
+}
+
+// handleDecrypt implements POST /v1/tlock/decrypt/{name}. The
+// application/octet-stream form streams the ciphertext straight from r.Body
+// to w the same way handleEncrypt's does; since the network can't be
+// guessed by trying each one in turn without buffering the body, it takes
+// chainhash as a query parameter instead of trying every configured network
+// the way the legacy JSON path's decryptBytes does. The parameter may be
+// left out only when exactly one network is configured.
+func (s *Server) handleDecrypt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		s.handleDecryptStream(w, r)
+		return
+	}
+
+	var req decryptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decode request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(req.CiphertextB64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decode ciphertext_b64: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	plaintext, err := s.decryptBytes(r.Context(), ciphertext)
+	if err != nil {
+		if errors.Is(err, tlock.ErrTooEarly) {
+			http.Error(w, err.Error(), http.StatusTooEarly)
+			return
+		}
+		http.Error(w, fmt.Sprintf("decrypt: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, decryptResponse{PlaintextB64: base64.StdEncoding.EncodeToString(plaintext)})
+}
+
+// handleDecryptStream handles the application/octet-stream form of
+// handleDecrypt.
+func (s *Server) handleDecryptStream(w http.ResponseWriter, r *http.Request) {
+	network, err := s.networkFor(r.URL.Query().Get("chainhash"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dec := tlock.NewDecrypter(network, aead.DataDecrypter{}, base.Decoder{})
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if err := dec.Decrypt(r.Context(), w, r.Body, false); err != nil {
+		if errors.Is(err, tlock.ErrTooEarly) {
+			http.Error(w, err.Error(), http.StatusTooEarly)
+			return
+		}
+		http.Error(w, fmt.Sprintf("decrypt: %s", err), http.StatusInternalServerError)
+		return
+	}
+}
+
+// decryptBytes tries every configured network in turn, since a ciphertext's
+// chainhash isn't known to the caller up front. A mismatched network now
+// fails fast with age.ErrIncorrectIdentity under the hood rather than a hard
+// error, so this just keeps trying until one network's stanza matches.
+func (s *Server) decryptBytes(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var lastErr error
+
+	for _, network := range s.networks {
+		plaintext, err := tlock.DecryptBytes(ctx, network, ciphertext)
+		if err == nil {
+			return plaintext, nil
+		}
+
+		lastErr = err
+		if errors.Is(err, tlock.ErrTooEarly) {
+			return nil, err
+		}
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no networks configured")
+	}
+
+	return nil, lastErr
+}
+
+// =============================================================================
+
+type roundsResponse struct {
+	Round uint64 `json:"round"`
+}
+
+// handleRounds implements GET /v1/tlock/rounds?at=<rfc3339>&chainhash=<hash>.
+func (s *Server) handleRounds(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	at := r.URL.Query().Get("at")
+	if at == "" {
+		http.Error(w, "at is required", http.StatusBadRequest)
+		return
+	}
+
+	t, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("parse at: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	network, err := s.networkFor(r.URL.Query().Get("chainhash"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	round, err := network.RoundNumber(r.Context(), t)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("round number: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, roundsResponse{Round: round})
+}
+
+// =============================================================================
+
+type batchDecryptRequest struct {
+	CiphertextsB64 []string `json:"ciphertexts_b64"`
+}
+
+type batchDecryptResult struct {
+	PlaintextB64 string `json:"plaintext_b64,omitempty"`
+	TooEarly     bool   `json:"too_early,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+type batchDecryptResponse struct {
+	Results []batchDecryptResult `json:"results"`
+}
+
+// handleBatchDecrypt implements POST /v1/tlock/batch_decrypt, decrypting
+// whichever ciphertexts are ready and reporting the rest as too_early
+// instead of failing the whole batch.
+func (s *Server) handleBatchDecrypt(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req batchDecryptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("decode request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	results := make([]batchDecryptResult, len(req.CiphertextsB64))
+	for i, ciphertextB64 := range req.CiphertextsB64 {
+		ciphertext, err := base64.StdEncoding.DecodeString(ciphertextB64)
+		if err != nil {
+			results[i] = batchDecryptResult{Error: fmt.Sprintf("decode ciphertext_b64: %s", err)}
+			continue
+		}
+
+		plaintext, err := s.decryptBytes(r.Context(), ciphertext)
+		switch {
+		case err == nil:
+			results[i] = batchDecryptResult{PlaintextB64: base64.StdEncoding.EncodeToString(plaintext)}
+		case errors.Is(err, tlock.ErrTooEarly):
+			results[i] = batchDecryptResult{TooEarly: true}
+		default:
+			results[i] = batchDecryptResult{Error: err.Error()}
+		}
+	}
+
+	writeJSON(w, http.StatusOK, batchDecryptResponse{Results: results})
+}
+
+// =============================================================================
+
+// writeJSON encodes v directly onto w, avoiding an intermediate buffer.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,383 @@
+// Package stream implements a STREAM-style chunked re-encryption on top of
+// tlock, inspired by age's STREAM construction. The plaintext is split into
+// fixed-size segments and each segment is timelock-encrypted to a different,
+// caller-scheduled drand round, so a file can be gradually disclosed as
+// rounds come due (auction reveals, timed leaks, ...) instead of unlocking
+// all at once like a single-round tlock file.
+package stream
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/drand/tlock"
+)
+
+// magic identifies a tlock stream file.
+var magic = [8]byte{'t', 'l', 'o', 'c', 'k', 's', 't', 'm'}
+
+// SegmentSize is the default size, in bytes, of each plaintext segment.
+const SegmentSize = 64 * 1024
+
+// Sanity bounds on length-prefixed fields read back from an untrusted
+// stream, so a corrupted or adversarial header can't force a multi-GB
+// allocation before the read that would actually fail on truncated input.
+// None of these are reachable by a stream this package itself produces.
+const (
+	maxStringLen    = 1 << 16 // chain hashes are a few dozen bytes
+	maxSegmentCount = 1 << 20 // a million segments is already absurd
+	maxRecordLen    = 1 << 30 // 1 GiB; generous for one segment
+)
+
+// Schedule describes which round unlocks each segment: segment i unlocks at
+// StartRound + i*Stride. Both fields are written to the stream's header, so
+// a file's disclosure schedule can be inspected without decrypting it.
+type Schedule struct {
+	StartRound uint64
+	Stride     uint64
+}
+
+// LinearSchedule constructs a Schedule where segment i unlocks at
+// startRound + i*stride.
+func LinearSchedule(startRound, stride uint64) Schedule {
+	return Schedule{StartRound: startRound, Stride: stride}
+}
+
+// Round returns the drand round that unlocks segment i.
+func (s Schedule) Round(segment int) uint64 {
+	return s.StartRound + uint64(segment)*s.Stride
+}
+
+// header is the framing written once at the start of a stream file: magic,
+// chain hash, segment size, segment count (implicit in len(offsets)),
+// startRound, and stride. offsets records, for every segment, the byte
+// offset of its length-prefixed record in the stream, so a Decrypter can
+// seek directly to any segment to resume.
+type header struct {
+	segmentSize int
+	chainHash   string
+	schedule    Schedule
+	offsets     []int64
+}
+
+// =============================================================================
+
+// Encrypter splits a plaintext into fixed-size segments and timelock
+// encrypts each one to the round a Schedule assigns it.
+type Encrypter struct {
+	network       tlock.Network
+	dataEncrypter tlock.DataEncrypter
+	encoder       tlock.Encoder
+
+	// SegmentSize is the size, in bytes, each plaintext chunk is split
+	// into before encryption. It defaults to SegmentSize and may be
+	// lowered, e.g. in tests, to exercise more segments with less data.
+	SegmentSize int
+}
+
+// NewEncrypter constructs a stream Encrypter. Each segment is encrypted
+// using a plain tlock.Encrypter built from the same network, data encrypter,
+// and encoder.
+func NewEncrypter(network tlock.Network, dataEncrypter tlock.DataEncrypter, encoder tlock.Encoder) *Encrypter {
+	return &Encrypter{
+		network:       network,
+		dataEncrypter: dataEncrypter,
+		encoder:       encoder,
+		SegmentSize:   SegmentSize,
+	}
+}
+
+// Encrypt reads src in SegmentSize chunks, encrypts segment i to
+// schedule(i), and writes the framed stream to dst. src is read to
+// completion before anything is written, since the header records every
+// segment's offset up front.
+func (e *Encrypter) Encrypt(ctx context.Context, dst io.Writer, src io.Reader, chainHash string, schedule Schedule) error {
+	segments, err := splitSegments(src, e.SegmentSize)
+	if err != nil {
+		return fmt.Errorf("split segments: %w", err)
+	}
+
+	enc := tlock.NewEncrypter(e.network, e.dataEncrypter, e.encoder)
+
+	records := make([][]byte, len(segments))
+	for i, segment := range segments {
+		var buf bytes.Buffer
+		if err := enc.Encrypt(ctx, &buf, bytes.NewReader(segment), schedule.Round(i), false); err != nil {
+			return fmt.Errorf("encrypt segment %d: %w", i, err)
+		}
+
+		records[i] = buf.Bytes()
+	}
+
+	return writeStream(dst, e.SegmentSize, chainHash, schedule, records)
+}
+
+// =============================================================================
+
+// Decrypter unlocks segments of a stream file as their round comes due.
+type Decrypter struct {
+	network       tlock.Network
+	dataDecrypter tlock.DataDecrypter
+	decoder       tlock.Decoder
+}
+
+// NewDecrypter constructs a stream Decrypter.
+func NewDecrypter(network tlock.Network, dataDecrypter tlock.DataDecrypter, decoder tlock.Decoder) *Decrypter {
+	return &Decrypter{
+		network:       network,
+		dataDecrypter: dataDecrypter,
+		decoder:       decoder,
+	}
+}
+
+// Decrypt reads src starting at segment resumeFrom (0 on the first call),
+// writing every segment it can unlock, in order, to dst. As soon as it
+// reaches a segment whose round isn't available yet it stops and returns
+// that segment's index along with tlock.ErrTooEarly; pass that index back in
+// as resumeFrom on a later call, once more beacons exist, to continue where
+// it left off. A nextSegment equal to the total segment count means the
+// whole stream has been unlocked.
+func (d *Decrypter) Decrypt(ctx context.Context, dst io.Writer, src io.ReadSeeker, resumeFrom int) (nextSegment int, err error) {
+	h, err := readHeader(src)
+	if err != nil {
+		return resumeFrom, fmt.Errorf("read header: %w", err)
+	}
+
+	if h.chainHash != d.network.ChainHash() {
+		return resumeFrom, fmt.Errorf("chainhash mismatch: stream is for %q, network is %q", h.chainHash, d.network.ChainHash())
+	}
+
+	if resumeFrom < 0 || resumeFrom > len(h.offsets) {
+		return resumeFrom, fmt.Errorf("resume segment %d out of range [0,%d]", resumeFrom, len(h.offsets))
+	}
+
+	dec := tlock.NewDecrypter(d.network, d.dataDecrypter, d.decoder)
+
+	for i := resumeFrom; i < len(h.offsets); i++ {
+		if _, err := src.Seek(h.offsets[i], io.SeekStart); err != nil {
+			return i, fmt.Errorf("seek segment %d: %w", i, err)
+		}
+
+		record, err := readRecord(src)
+		if err != nil {
+			return i, fmt.Errorf("read segment %d: %w", i, err)
+		}
+
+		var plain bytes.Buffer
+		if err := dec.Decrypt(ctx, &plain, bytes.NewReader(record), false); err != nil {
+			if errors.Is(err, tlock.ErrTooEarly) {
+				return i, tlock.ErrTooEarly
+			}
+			return i, fmt.Errorf("decrypt segment %d: %w", i, err)
+		}
+
+		if _, err := dst.Write(plain.Bytes()); err != nil {
+			return i, fmt.Errorf("write segment %d: %w", i, err)
+		}
+	}
+
+	return len(h.offsets), nil
+}
+
+// =============================================================================
+
+// splitSegments reads src to completion and slices it into size-byte
+// chunks, the last of which may be shorter.
+func splitSegments(src io.Reader, size int) ([][]byte, error) {
+	var segments [][]byte
+
+	for {
+		buf := make([]byte, size)
+
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			segments = append(segments, buf[:n])
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return segments, nil
+}
+
+// writeStream writes the magic, chain hash, segment size, segment count,
+// schedule, segment offsets, and then every length-prefixed segment record
+// to dst.
+func writeStream(dst io.Writer, segmentSize int, chainHash string, schedule Schedule, records [][]byte) error {
+	if _, err := dst.Write(magic[:]); err != nil {
+		return fmt.Errorf("write magic: %w", err)
+	}
+
+	if err := writeString(dst, chainHash); err != nil {
+		return fmt.Errorf("write chainhash: %w", err)
+	}
+
+	if err := binary.Write(dst, binary.BigEndian, uint32(segmentSize)); err != nil {
+		return fmt.Errorf("write segment size: %w", err)
+	}
+
+	if err := binary.Write(dst, binary.BigEndian, uint32(len(records))); err != nil {
+		return fmt.Errorf("write segment count: %w", err)
+	}
+
+	if err := binary.Write(dst, binary.BigEndian, schedule.StartRound); err != nil {
+		return fmt.Errorf("write start round: %w", err)
+	}
+
+	if err := binary.Write(dst, binary.BigEndian, schedule.Stride); err != nil {
+		return fmt.Errorf("write stride: %w", err)
+	}
+
+	// The offset table is computed relative to the start of the segment
+	// data, which begins right after the table itself.
+	offsets := make([]int64, len(records))
+	var cursor int64
+	for i, record := range records {
+		offsets[i] = cursor
+		cursor += 4 + int64(len(record))
+	}
+
+	for _, offset := range offsets {
+		if err := binary.Write(dst, binary.BigEndian, offset); err != nil {
+			return fmt.Errorf("write offset table: %w", err)
+		}
+	}
+
+	for i, record := range records {
+		if err := writeRecord(dst, record); err != nil {
+			return fmt.Errorf("write segment %d: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+// readHeader parses the framing at the start of src and rewrites the
+// offsets so they're absolute positions in src rather than relative to the
+// start of the segment data.
+func readHeader(src io.ReadSeeker) (header, error) {
+	var gotMagic [8]byte
+	if _, err := io.ReadFull(src, gotMagic[:]); err != nil {
+		return header{}, fmt.Errorf("read magic: %w", err)
+	}
+	if gotMagic != magic {
+		return header{}, errors.New("not a tlock stream file")
+	}
+
+	chainHash, err := readString(src)
+	if err != nil {
+		return header{}, fmt.Errorf("read chainhash: %w", err)
+	}
+
+	var segmentSize, segmentCount uint32
+	if err := binary.Read(src, binary.BigEndian, &segmentSize); err != nil {
+		return header{}, fmt.Errorf("read segment size: %w", err)
+	}
+	if err := binary.Read(src, binary.BigEndian, &segmentCount); err != nil {
+		return header{}, fmt.Errorf("read segment count: %w", err)
+	}
+	if segmentCount > maxSegmentCount {
+		return header{}, fmt.Errorf("segment count %d exceeds sanity limit %d", segmentCount, maxSegmentCount)
+	}
+
+	var schedule Schedule
+	if err := binary.Read(src, binary.BigEndian, &schedule.StartRound); err != nil {
+		return header{}, fmt.Errorf("read start round: %w", err)
+	}
+	if err := binary.Read(src, binary.BigEndian, &schedule.Stride); err != nil {
+		return header{}, fmt.Errorf("read stride: %w", err)
+	}
+
+	offsets := make([]int64, segmentCount)
+	for i := range offsets {
+		if err := binary.Read(src, binary.BigEndian, &offsets[i]); err != nil {
+			return header{}, fmt.Errorf("read offset %d: %w", i, err)
+		}
+	}
+
+	dataStart, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return header{}, fmt.Errorf("tell data start: %w", err)
+	}
+
+	for i := range offsets {
+		offsets[i] += dataStart
+	}
+
+	return header{
+		segmentSize: int(segmentSize),
+		chainHash:   chainHash,
+		schedule:    schedule,
+		offsets:     offsets,
+	}, nil
+}
+
+// writeRecord writes a length-prefixed segment record.
+func writeRecord(dst io.Writer, record []byte) error {
+	if err := binary.Write(dst, binary.BigEndian, uint32(len(record))); err != nil {
+		return fmt.Errorf("write length: %w", err)
+	}
+
+	_, err := dst.Write(record)
+	return err
+}
+
+// readRecord reads a length-prefixed segment record at the reader's current
+// position.
+func readRecord(src io.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(src, binary.BigEndian, &length); err != nil {
+		return nil, fmt.Errorf("read length: %w", err)
+	}
+
+	if length > maxRecordLen {
+		return nil, fmt.Errorf("record length %d exceeds sanity limit %d", length, maxRecordLen)
+	}
+
+	record := make([]byte, length)
+	if _, err := io.ReadFull(src, record); err != nil {
+		return nil, fmt.Errorf("read body: %w", err)
+	}
+
+	return record, nil
+}
+
+// writeString writes a length-prefixed string.
+func writeString(dst io.Writer, s string) error {
+	if err := binary.Write(dst, binary.BigEndian, uint32(len(s))); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(dst, s)
+	return err
+}
+
+// readString reads a length-prefixed string.
+func readString(src io.Reader) (string, error) {
+	var length uint32
+	if err := binary.Read(src, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if length > maxStringLen {
+		return "", fmt.Errorf("string length %d exceeds sanity limit %d", length, maxStringLen)
+	}
+
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(src, buf); err != nil {
+		return "", err
+	}
+
+	return string(buf), nil
+}
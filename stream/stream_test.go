@@ -0,0 +1,99 @@
+package stream_test
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // Calls init function.
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/drand/tlock"
+	"github.com/drand/tlock/data_encrypters/aead"
+	"github.com/drand/tlock/encoders/base"
+	"github.com/drand/tlock/networks/http"
+	"github.com/drand/tlock/stream"
+)
+
+var (
+	//go:embed test_artifacts/data.txt
+	dataFile []byte
+)
+
+const (
+	testnetHost      = "http://pl-us.testnet.drand.sh/"
+	testnetChainHash = "7672797f548f3f4748ac4bf3352fc6c6b6468c9ad40ad456a397545c6e2df5bf"
+)
+
+// Test_GradualDisclosure encrypts data.txt split into tiny segments, each
+// locked to a later round than the last, and checks that decryption only
+// ever reveals the segments whose round has actually passed.
+func Test_GradualDisclosure(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping testing in short mode")
+	}
+
+	network := http.NewNetwork(testnetHost, testnetChainHash)
+	ctx := context.Background()
+
+	// =========================================================================
+	// Encrypt
+
+	in, err := os.Open("test_artifacts/data.txt")
+	if err != nil {
+		t.Fatalf("reader error %s", err)
+	}
+	defer in.Close()
+
+	startRound, err := network.RoundNumber(ctx, time.Now().Add(4*time.Second))
+	if err != nil {
+		t.Fatalf("round by duration: %s", err)
+	}
+
+	enc := stream.NewEncrypter(network, aead.DataEncrypter{}, base.Encoder{})
+	enc.SegmentSize = 32
+
+	var cipherData bytes.Buffer
+	schedule := stream.LinearSchedule(startRound, 1)
+	if err := enc.Encrypt(ctx, &cipherData, in, testnetChainHash, schedule); err != nil {
+		t.Fatalf("encrypt error %s", err)
+	}
+
+	// =========================================================================
+	// Decrypt: too early, nothing should unlock yet
+
+	dec := stream.NewDecrypter(network, aead.DataDecrypter{}, base.Decoder{})
+
+	var early bytes.Buffer
+	src := bytes.NewReader(cipherData.Bytes())
+	next, err := dec.Decrypt(ctx, &early, src, 0)
+	if !errors.Is(err, tlock.ErrTooEarly) {
+		t.Fatalf("expecting ErrTooEarly; got %s", err)
+	}
+	if next != 0 {
+		t.Fatalf("expecting no segment unlocked yet; got %d", next)
+	}
+
+	// =========================================================================
+	// Decrypt: wait for every round in the schedule, then resume to the end
+
+	// With 5 segments on a 1-round stride, the last segment's round is 4
+	// rounds past startRound, so on the 3s testnet it is due within about
+	// 16s (the initial 4s delay plus 4 rounds); 25s leaves roughly 3 rounds
+	// of slack beyond that.
+	time.Sleep(25 * time.Second)
+
+	var rest bytes.Buffer
+	src = bytes.NewReader(cipherData.Bytes())
+	final, err := dec.Decrypt(ctx, &rest, src, next)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	full := append(early.Bytes(), rest.Bytes()...)
+	if !bytes.Equal(full, dataFile) {
+		t.Fatalf("decrypted stream is invalid; expected %d; got %d", len(dataFile), len(full))
+	}
+	_ = final
+}
@@ -0,0 +1,187 @@
+// Package tlock provides an API for encrypting/decrypting data using
+// drand timelock encryption. This allows data to be encrypted and only
+// decrypted in the future once the drand network reveals the signature
+// for the configured round.
+package tlock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"filippo.io/age"
+	"github.com/drand/kyber"
+)
+
+// ErrTooEarly represents an error when a decryption operation happens before
+// the specified round has been reached on the drand network.
+var ErrTooEarly = errors.New("too early to decrypt")
+
+// =============================================================================
+
+// Network represents the network support needed to run the tlock
+// encrypt/decrypt operations.
+type Network interface {
+	Host() string
+	ChainHash() string
+	PublicKey() kyber.Point
+	IsReadyToDecrypt(roundNumber uint64) (id []byte, ready bool)
+	RoundNumber(ctx context.Context, t time.Time) (uint64, error)
+	BeaconTime(roundNumber uint64) time.Time
+}
+
+// DataEncrypter knows how to encrypt the specified src data using the
+// provided key, writing the result to dst.
+type DataEncrypter interface {
+	Encrypt(dst io.Writer, src io.Reader, key []byte) error
+}
+
+// DataDecrypter knows how to decrypt the specified src data using the
+// provided key, writing the result to dst.
+type DataDecrypter interface {
+	Decrypt(dst io.Writer, src io.Reader, key []byte) error
+}
+
+// Encoder knows how to encode the cipher data so it can be represented in a
+// way that is compatible with the age file format.
+type Encoder interface {
+	Encode(dst io.Writer, armor bool) io.WriteCloser
+}
+
+// Decoder knows how to decode data that was encoded by an Encoder.
+type Decoder interface {
+	Decode(src io.Reader) (io.Reader, error)
+}
+
+// =============================================================================
+
+// Encrypter provides an API for encrypting data that can only be decrypted
+// in the future once the specified round's beacon is available.
+type Encrypter struct {
+	network         Network
+	dataEncrypter   DataEncrypter
+	encoder         Encoder
+	extraRecipients []age.Recipient
+}
+
+// NewEncrypter constructs an Encrypter for the specified network, data
+// encrypter, and encoder.
+func NewEncrypter(network Network, dataEncrypter DataEncrypter, encoder Encoder) *Encrypter {
+	return &Encrypter{
+		network:       network,
+		dataEncrypter: dataEncrypter,
+		encoder:       encoder,
+	}
+}
+
+// Encrypt reads the plaintext from src and writes the timelock encrypted
+// result to dst. The data can be decrypted once roundNumber has been reached
+// on the configured network, or by any of the extra recipients, if any were
+// configured with WithExtraRecipients.
+func (e *Encrypter) Encrypt(ctx context.Context, dst io.Writer, src io.Reader, roundNumber uint64, armor bool) error {
+	recipients := append([]age.Recipient{
+		&tleRecipient{
+			round:   roundNumber,
+			network: e.network,
+		},
+	}, e.extraRecipients...)
+
+	w := e.encoder.Encode(dst, armor)
+	defer w.Close()
+
+	ageWriter, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return fmt.Errorf("age encrypt: %w", err)
+	}
+	defer ageWriter.Close()
+
+	if err := e.dataEncrypter.Encrypt(ageWriter, src, nil); err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+
+	return nil
+}
+
+// WithExtraRecipients configures additional age.Recipients (x25519, scrypt
+// passphrase, ssh, ...) that will be able to decrypt the file independently
+// of the timelock round, producing a standard age multi-recipient file.
+// Because Encrypt always adds the timelock recipient as well, an
+// *age.ScryptRecipient makes Encrypt fail: age.Encrypt refuses a scrypt
+// recipient alongside any other.
+func (e *Encrypter) WithExtraRecipients(recipients ...age.Recipient) *Encrypter {
+	e.extraRecipients = append(e.extraRecipients, recipients...)
+	return e
+}
+
+// =============================================================================
+
+// Decrypter provides an API for decrypting data that was encrypted by the
+// Encrypter.
+type Decrypter struct {
+	network       Network
+	dataDecrypter DataDecrypter
+	decoder       Decoder
+	wait          bool
+}
+
+// DecrypterOption mutates a Decrypter's behavior at construction time.
+type DecrypterOption func(*Decrypter)
+
+// WithWait configures the Decrypter to block until the requested round's
+// beacon becomes available, polling the network with backoff, instead of
+// returning ErrTooEarly immediately.
+func WithWait(wait bool) DecrypterOption {
+	return func(d *Decrypter) {
+		d.wait = wait
+	}
+}
+
+// NewDecrypter constructs a Decrypter for the specified network, data
+// decrypter, and decoder.
+func NewDecrypter(network Network, dataDecrypter DataDecrypter, decoder Decoder, opts ...DecrypterOption) *Decrypter {
+	d := &Decrypter{
+		network:       network,
+		dataDecrypter: dataDecrypter,
+		decoder:       decoder,
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Decrypt reads the timelock encrypted data from src and writes the
+// plaintext to dst. ErrTooEarly itself is returned if the round has not
+// been reached yet on the configured network, unless the Decrypter was
+// constructed with WithWait(true), in which case Decrypt blocks until the
+// round's beacon is available or ctx is canceled.
+func (d *Decrypter) Decrypt(ctx context.Context, dst io.Writer, src io.Reader, armor bool) error {
+	identity := &tleIdentity{
+		ctx:     ctx,
+		network: d.network,
+		wait:    d.wait,
+	}
+
+	r, err := d.decoder.Decode(src)
+	if err != nil {
+		return fmt.Errorf("decode: %w", err)
+	}
+
+	ageReader, err := age.Decrypt(r, identity)
+	if err != nil {
+		if errors.Is(err, ErrTooEarly) {
+			return ErrTooEarly
+		}
+		return fmt.Errorf("age decrypt: %w", err)
+	}
+
+	if err := d.dataDecrypter.Decrypt(dst, ageReader, nil); err != nil {
+		return fmt.Errorf("decrypt: %w", err)
+	}
+
+	return nil
+}
@@ -1,10 +1,13 @@
 package tlock
 
 import (
+	"context"
 	"crypto/sha256"
 	"errors"
 	"fmt"
+	"math/rand"
 	"strconv"
+	"time"
 
 	"filippo.io/age"
 	"github.com/drand/drand/chain"
@@ -85,19 +88,38 @@ func calculateEncryptionID(roundNumber uint64) ([]byte, error) {
 // tleIdentity implements the age Identity interface. This is used to decrypt
 // data with the age Decrypt API.
 type tleIdentity struct {
+	ctx     context.Context
 	network Network
+	wait    bool
 }
 
-// Unwrap is called by the age Decrypt API and is provided the DEK that was time
-// lock encrypted by the Wrap function via the Stanza. Inside of Unwrap we decrypt
-// the DEK and provide back to age.
+// Unwrap is called by the age Decrypt API and is provided all the stanzas
+// found in the age file. A file produced alongside other age recipients
+// (x25519, scrypt, ssh, ...) will contain stanzas this identity doesn't own,
+// so every non-tlock stanza, and every tlock stanza for a different network,
+// is skipped with ErrIncorrectIdentity to let age fall through to the next
+// identity. Only a tlock stanza matching our network is actually decrypted.
 func (t *tleIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
-	if len(stanzas) != 1 {
-		return nil, errors.New("check stanzas length: should be one")
+	for _, stanza := range stanzas {
+		fileKey, err := t.unwrapStanza(stanza)
+		if err != nil {
+			if errors.Is(err, age.ErrIncorrectIdentity) {
+				continue
+			}
+			return nil, err
+		}
+
+		return fileKey, nil
 	}
 
-	stanza := stanzas[0]
+	return nil, age.ErrIncorrectIdentity
+}
 
+// unwrapStanza attempts to decrypt a single stanza. It returns
+// age.ErrIncorrectIdentity, wrapped, whenever the stanza clearly isn't meant
+// for this identity, so callers can move on to the next stanza instead of
+// aborting the whole file.
+func (t *tleIdentity) unwrapStanza(stanza *age.Stanza) ([]byte, error) {
 	if stanza.Type != "tlock" {
 		return nil, fmt.Errorf("check stanza type: wrong type: %w", age.ErrIncorrectIdentity)
 	}
@@ -112,7 +134,7 @@ func (t *tleIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
 	}
 
 	if t.network.ChainHash() != stanza.Args[1] {
-		return nil, errors.New("wrong chainhash")
+		return nil, fmt.Errorf("check chainhash: wrong network: %w", age.ErrIncorrectIdentity)
 	}
 
 	cipherDEK, err := parseCipherDEK(stanza.Body)
@@ -120,7 +142,7 @@ func (t *tleIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
 		return nil, fmt.Errorf("parse cipher dek: %w", err)
 	}
 
-	fileKey, err := decryptDEK(cipherDEK, t.network, blockRound)
+	fileKey, err := decryptDEK(t.ctx, cipherDEK, t.network, blockRound, t.wait)
 	if err != nil {
 		return nil, fmt.Errorf("decrypt dek: %w", err)
 	}
@@ -155,11 +177,20 @@ func parseCipherDEK(stanzaBody []byte) (cipherDEK, error) {
 }
 
 // decryptDEK attempts to decrypt an encrypted DEK against the provided network
-// for the specified round.
-func decryptDEK(cipherDEK cipherDEK, network Network, roundNumber uint64) (fileKey []byte, err error) {
+// for the specified round. When wait is true and the round isn't available
+// yet, decryptDEK blocks until it is instead of returning ErrTooEarly, see
+// blockUntilReady.
+func decryptDEK(ctx context.Context, cipherDEK cipherDEK, network Network, roundNumber uint64, wait bool) (fileKey []byte, err error) {
 	id, ready := network.IsReadyToDecrypt(roundNumber)
 	if !ready {
-		return nil, ErrTooEarly
+		if !wait {
+			return nil, ErrTooEarly
+		}
+
+		id, err = blockUntilReady(ctx, network, roundNumber)
+		if err != nil {
+			return nil, fmt.Errorf("wait for round %d: %w", roundNumber, err)
+		}
 	}
 
 	b := chain.Beacon{
@@ -197,3 +228,125 @@ func decryptDEK(cipherDEK cipherDEK, network Network, roundNumber uint64) (fileK
 
 	return fileKey, nil
 }
+
+// =============================================================================
+
+// Retry/backoff tuning for blockUntilReady, modeled after the ACME client's
+// polling behavior: wait for the round's known beacon time, then poll with
+// an exponentially growing delay capped at backoffCeiling, plus jitter so a
+// crowd of waiting decrypters doesn't all poll in lockstep.
+const (
+	backoffBase     = 250 * time.Millisecond
+	backoffCeiling  = 10 * time.Second
+	backoffJitter   = time.Second
+	backoffMaxShift = 6 // 250ms * 2^6 == 16s, already above the ceiling
+)
+
+// retryAfterNetwork is optionally implemented by a Network that can surface
+// a server-provided Retry-After hint (e.g. the HTTP network responding to a
+// rate-limited or not-yet-available beacon request).
+type retryAfterNetwork interface {
+	Network
+	RetryAfter() (time.Duration, bool)
+}
+
+// NetworkError may be returned by a readyChecker's IsReadyToDecryptErr to
+// tell blockUntilReady whether the failure is worth polling again for. A
+// Network whose errors don't implement NetworkError are treated as
+// transient, the same as a plain network blip.
+type NetworkError interface {
+	error
+	Retryable() bool
+}
+
+// readyChecker is optionally implemented by a Network that can surface the
+// error behind a failed readiness check instead of a plain bool. Without
+// it, blockUntilReady has no way to tell a permanent failure (e.g. a 4xx
+// response) from a transient one and would poll forever; with it, a
+// non-retryable NetworkError aborts the loop immediately.
+type readyChecker interface {
+	Network
+	IsReadyToDecryptErr(roundNumber uint64) (id []byte, err error)
+}
+
+// blockUntilReady waits for roundNumber's beacon to become available on
+// network, first sleeping until the round's expected beacon time and then
+// polling for it with exponential backoff. It returns as soon as the beacon
+// shows up, ctx is canceled, or the network reports a non-retryable error.
+func blockUntilReady(ctx context.Context, network Network, roundNumber uint64) ([]byte, error) {
+	if wait := time.Until(network.BeaconTime(roundNumber)); wait > 0 {
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+
+	checker, classifies := network.(readyChecker)
+
+	for attempt := 0; ; attempt++ {
+		if classifies {
+			id, err := checker.IsReadyToDecryptErr(roundNumber)
+			if err == nil {
+				return id, nil
+			}
+			if !retryable(err) {
+				return nil, err
+			}
+		} else if id, ready := network.IsReadyToDecrypt(roundNumber); ready {
+			return id, nil
+		}
+
+		if err := sleep(ctx, nextBackoff(attempt, network)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether err is worth polling again for: ErrTooEarly,
+// any error a NetworkError classifies as Retryable (transient errors and
+// 429s), or any error that doesn't implement NetworkError at all. A 4xx
+// NetworkError with Retryable() false (anything but 429 or "beacon not yet
+// available") is the only thing that aborts the loop.
+func retryable(err error) bool {
+	if errors.Is(err, ErrTooEarly) {
+		return true
+	}
+
+	var netErr NetworkError
+	if errors.As(err, &netErr) {
+		return netErr.Retryable()
+	}
+
+	return true
+}
+
+// nextBackoff computes the delay before the next poll, honoring a
+// Retry-After hint from the network when one is available.
+func nextBackoff(attempt int, network Network) time.Duration {
+	shift := attempt
+	if shift > backoffMaxShift {
+		shift = backoffMaxShift
+	}
+
+	delay := backoffBase * time.Duration(uint64(1)<<uint(shift))
+	if delay > backoffCeiling {
+		delay = backoffCeiling
+	}
+
+	if rn, ok := network.(retryAfterNetwork); ok {
+		if hint, ok := rn.RetryAfter(); ok && hint > delay {
+			delay = hint
+		}
+	}
+
+	return delay + time.Duration(rand.Int63n(int64(backoffJitter)))
+}
+
+// sleep waits for d, returning ctx.Err() if ctx is canceled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,65 @@
+package tlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestUnwrap_SkipsNonTlockStanza(t *testing.T) {
+	identity := &tleIdentity{
+		ctx:     context.Background(),
+		network: &fakeNetwork{chainHash: "test-chain"},
+	}
+
+	stanzas := []*age.Stanza{
+		{Type: "X25519", Args: []string{"some-arg"}, Body: []byte("ignored")},
+	}
+
+	if _, err := identity.Unwrap(stanzas); !errors.Is(err, age.ErrIncorrectIdentity) {
+		t.Fatalf("expected age.ErrIncorrectIdentity, got %v", err)
+	}
+}
+
+func TestUnwrap_SkipsMismatchedChainHash(t *testing.T) {
+	identity := &tleIdentity{
+		ctx:     context.Background(),
+		network: &fakeNetwork{chainHash: "test-chain"},
+	}
+
+	stanzas := []*age.Stanza{
+		{
+			Type: "tlock",
+			Args: []string{"1", "other-chain"},
+			Body: make([]byte, kyberPointLen+cipherVLen+cipherWLen),
+		},
+	}
+
+	if _, err := identity.Unwrap(stanzas); !errors.Is(err, age.ErrIncorrectIdentity) {
+		t.Fatalf("expected age.ErrIncorrectIdentity, got %v", err)
+	}
+}
+
+func TestUnwrap_FallsThroughToMatchingStanza(t *testing.T) {
+	// Two stanzas: one for an unrelated network, one that fails for a
+	// reason unrelated to identity matching (a malformed body). Unwrap
+	// should skip the first (wrong chainhash) but return the second's
+	// real, non-ErrIncorrectIdentity error rather than the generic
+	// ErrIncorrectIdentity it would return if no stanza matched at all.
+	identity := &tleIdentity{
+		ctx:     context.Background(),
+		network: &fakeNetwork{chainHash: "test-chain"},
+	}
+
+	stanzas := []*age.Stanza{
+		{Type: "tlock", Args: []string{"1", "other-chain"}, Body: make([]byte, kyberPointLen+cipherVLen+cipherWLen)},
+		{Type: "tlock", Args: []string{"1", "test-chain"}, Body: []byte("too-short")},
+	}
+
+	_, err := identity.Unwrap(stanzas)
+	if err == nil || errors.Is(err, age.ErrIncorrectIdentity) {
+		t.Fatalf("expected a parse error from the matching stanza, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package tlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNextBackoff_ExponentialWithJitter(t *testing.T) {
+	network := &fakeNetwork{}
+
+	for attempt, want := range map[int]time.Duration{
+		0: backoffBase,
+		1: 2 * backoffBase,
+		2: 4 * backoffBase,
+	} {
+		got := nextBackoff(attempt, network)
+		if got < want || got >= want+backoffJitter {
+			t.Fatalf("attempt %d: got %s, want in [%s,%s)", attempt, got, want, want+backoffJitter)
+		}
+	}
+}
+
+func TestNextBackoff_CapsAtCeiling(t *testing.T) {
+	network := &fakeNetwork{}
+
+	got := nextBackoff(backoffMaxShift+10, network)
+	if got < backoffCeiling || got >= backoffCeiling+backoffJitter {
+		t.Fatalf("got %s, want in [%s,%s)", got, backoffCeiling, backoffCeiling+backoffJitter)
+	}
+}
+
+func TestNextBackoff_HonorsRetryAfterHint(t *testing.T) {
+	network := &fakeNetwork{retryAfter: 5 * time.Second, hasRetryAfter: true}
+
+	got := nextBackoff(0, network)
+	if got < 5*time.Second {
+		t.Fatalf("got %s, want at least the 5s Retry-After hint", got)
+	}
+}
+
+func TestBlockUntilReady_RetriesTransientErrorThenSucceeds(t *testing.T) {
+	network := &fakeNetwork{
+		beaconTime: time.Now().Add(-time.Millisecond),
+		errSequence: []error{
+			&fakeNetworkError{msg: "rate limited", retryable: true},
+		},
+	}
+
+	id, err := blockUntilReady(context.Background(), network, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(id) != "fake-signature" {
+		t.Fatalf("unexpected id: %q", id)
+	}
+}
+
+func TestBlockUntilReady_AbortsOnNonRetryableError(t *testing.T) {
+	network := &fakeNetwork{
+		beaconTime: time.Now().Add(-time.Millisecond),
+		errSequence: []error{
+			&fakeNetworkError{msg: "bad request", retryable: false},
+		},
+	}
+
+	_, err := blockUntilReady(context.Background(), network, 1)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	var netErr NetworkError
+	if !errors.As(err, &netErr) || netErr.Retryable() {
+		t.Fatalf("expected a non-retryable NetworkError, got %v", err)
+	}
+}
+
+func TestBlockUntilReady_FallsBackToBoolOnlyNetwork(t *testing.T) {
+	network := &plainFakeNetwork{
+		beaconTime: time.Now().Add(-time.Millisecond),
+		readyAfter: 1,
+	}
+
+	id, err := blockUntilReady(context.Background(), network, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(id) != "fake-signature" {
+		t.Fatalf("unexpected id: %q", id)
+	}
+}
+
+func TestBlockUntilReady_CanceledContext(t *testing.T) {
+	network := &fakeNetwork{beaconTime: time.Now().Add(time.Hour)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := blockUntilReady(ctx, network, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
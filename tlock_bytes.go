@@ -0,0 +1,39 @@
+package tlock
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/drand/tlock/data_encrypters/aead"
+	"github.com/drand/tlock/encoders/base"
+)
+
+// EncryptBytes is a convenience wrapper around Encrypter for callers that
+// already hold the full plaintext in memory, such as the tlockd service,
+// rather than wanting to manage an io.Reader/io.Writer pair themselves. It
+// always uses the standard AEAD data encrypter and base (non-armored)
+// encoder.
+func EncryptBytes(ctx context.Context, network Network, plaintext []byte, roundNumber uint64) ([]byte, error) {
+	var cipherData bytes.Buffer
+
+	enc := NewEncrypter(network, aead.DataEncrypter{}, base.Encoder{})
+	if err := enc.Encrypt(ctx, &cipherData, bytes.NewReader(plaintext), roundNumber, false); err != nil {
+		return nil, err
+	}
+
+	return cipherData.Bytes(), nil
+}
+
+// DecryptBytes is the EncryptBytes counterpart for callers that want a
+// []byte in, []byte out API. It returns ErrTooEarly the same way
+// Decrypter.Decrypt does.
+func DecryptBytes(ctx context.Context, network Network, ciphertext []byte) ([]byte, error) {
+	var plainData bytes.Buffer
+
+	dec := NewDecrypter(network, aead.DataDecrypter{}, base.Decoder{})
+	if err := dec.Decrypt(ctx, &plainData, bytes.NewReader(ciphertext), false); err != nil {
+		return nil, err
+	}
+
+	return plainData.Bytes(), nil
+}
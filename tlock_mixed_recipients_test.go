@@ -0,0 +1,63 @@
+package tlock_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"filippo.io/age"
+
+	"github.com/drand/tlock"
+	"github.com/drand/tlock/data_encrypters/aead"
+	"github.com/drand/tlock/encoders/base"
+	"github.com/drand/tlock/networks/http"
+)
+
+// Test_MixedRecipients verifies the "standard age composition pattern"
+// WithExtraRecipients exists to enable: a file encrypted with both a tlock
+// recipient and a side age recipient can be opened by the side recipient on
+// its own, without ever waiting on the timelock round. That only works if
+// Unwrap skips the stanzas it doesn't own with age.ErrIncorrectIdentity
+// instead of hard-failing the whole file.
+func Test_MixedRecipients(t *testing.T) {
+	network := http.NewNetwork(testnetHost, testnetChainHash)
+	ctx := context.Background()
+
+	x25519Identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatalf("generate x25519 identity: %s", err)
+	}
+
+	// Far enough out that this test never accidentally waits for the
+	// round to actually arrive.
+	futureRound, err := network.RoundNumber(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("round by duration: %s", err)
+	}
+
+	var cipherData bytes.Buffer
+	enc := tlock.NewEncrypter(network, aead.DataEncrypter{}, base.Encoder{}).
+		WithExtraRecipients(x25519Identity.Recipient())
+
+	if err := enc.Encrypt(ctx, &cipherData, bytes.NewReader(dataFile), futureRound, false); err != nil {
+		t.Fatalf("encrypt error %s", err)
+	}
+
+	// Decrypt with only the x25519 identity: age must skip the tlock
+	// stanza via ErrIncorrectIdentity and fall through to the matching
+	// x25519 stanza instead of hard-failing on the stanza it can't use.
+	ageReader, err := age.Decrypt(&cipherData, x25519Identity)
+	if err != nil {
+		t.Fatalf("age decrypt: %s", err)
+	}
+
+	var plainData bytes.Buffer
+	if _, err := plainData.ReadFrom(ageReader); err != nil {
+		t.Fatalf("read plaintext: %s", err)
+	}
+
+	if !bytes.Equal(plainData.Bytes(), dataFile) {
+		t.Fatalf("decrypted file is invalid; expected %d; got %d", len(dataFile), len(plainData.Bytes()))
+	}
+}